@@ -0,0 +1,399 @@
+// The diff package computes structural diffs between two drill.Node trees,
+// modeled after the recursive comparison used by a merkletrie: subtrees are
+// hashed so that identical content can be skipped, and only the paths that
+// differ are descended into.
+package diff
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/anaminus/drill"
+)
+
+// Op identifies the kind of change recorded by a Change.
+type Op int
+
+const (
+	// Insert indicates that New was added at Path.
+	Insert Op = iota
+	// Delete indicates that Old was removed from Path.
+	Delete
+	// Modify indicates that the leaf at Path changed from Old to New.
+	Modify
+	// Move indicates that Old moved from From to Path, becoming New.
+	Move
+)
+
+// String returns the name of op.
+func (op Op) String() string {
+	switch op {
+	case Insert:
+		return "Insert"
+	case Delete:
+		return "Delete"
+	case Modify:
+		return "Modify"
+	case Move:
+		return "Move"
+	default:
+		return fmt.Sprintf("Op(%d)", int(op))
+	}
+}
+
+// Change describes a single difference between two trees. Path is a
+// slash-delimited path of names and "[i]" ordered indices, in the style of
+// drill.Path.String, relative to the roots being compared.
+type Change struct {
+	Op Op
+	// Path is the location of the change.
+	Path string
+	// From is the location Old moved from. Only set for Move.
+	From string
+	// Old is the node removed, moved, or replaced by this change. Nil for
+	// Insert.
+	Old drill.Node
+	// New is the node inserted, moved to, or used as a replacement by this
+	// change. Nil for Delete.
+	New drill.Node
+}
+
+// Hasher computes a content hash for n, given the hashes already computed for
+// its children, in order for an OrderedBranch, or sorted by name for an
+// UnorderedBranch. It is used to short-circuit subtrees that are identical on
+// both sides of a comparison.
+type Hasher func(n drill.Node, childHashes [][]byte) []byte
+
+// DefaultHasher hashes the fragment of n, read via FragmentReader if n
+// implements drill.ReaderNode, followed by the hashes of its children.
+func DefaultHasher(n drill.Node, childHashes [][]byte) []byte {
+	h := sha256.New()
+	io.WriteString(h, fragment(n))
+	for _, c := range childHashes {
+		h.Write(c)
+	}
+	return h.Sum(nil)
+}
+
+// fragment returns the fragment of n, preferring FragmentReader when
+// available so that large fragments need not be held in memory twice.
+func fragment(n drill.Node) string {
+	if n == nil {
+		return ""
+	}
+	if r, ok := n.(drill.ReaderNode); ok {
+		if rc, err := r.FragmentReader(); err == nil {
+			defer rc.Close()
+			var buf strings.Builder
+			io.Copy(&buf, rc)
+			return buf.String()
+		}
+	}
+	return n.Fragment()
+}
+
+// Differ computes structural diffs between two drill.Node trees.
+type Differ struct {
+	// Hasher computes the content hashes used to short-circuit identical
+	// subtrees. Defaults to DefaultHasher if nil.
+	Hasher Hasher
+}
+
+// Diff compares the trees rooted at a and b using the default Hasher,
+// returning the changes required to transform a into b.
+func Diff(a, b drill.Node) []Change {
+	return (&Differ{}).Diff(a, b)
+}
+
+func (d *Differ) hasher() Hasher {
+	if d.Hasher != nil {
+		return d.Hasher
+	}
+	return DefaultHasher
+}
+
+// Diff compares the trees rooted at a and b, returning the changes required
+// to transform a into b.
+func (d *Differ) Diff(a, b drill.Node) []Change {
+	var changes []Change
+	d.diff(a, b, "", &changes)
+	return changes
+}
+
+// hash computes the content hash of n and its descendants. It walks the same
+// branch, in the same priority (UnorderedBranch over OrderedBranch), that
+// diff uses to compare n against its counterpart, so that the short-circuit
+// hash always covers every child diff would otherwise visit.
+func (d *Differ) hash(n drill.Node) []byte {
+	if n == nil {
+		return nil
+	}
+	var childHashes [][]byte
+	if v, ok := n.(drill.UnorderedBranch); ok {
+		children := v.UnorderedChildren()
+		names := make([]string, 0, len(children))
+		for name := range children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			childHashes = append(childHashes, d.hash(children[name]))
+		}
+	}
+	if v, ok := n.(drill.OrderedBranch); ok && len(childHashes) == 0 {
+		for _, c := range v.OrderedChildren() {
+			childHashes = append(childHashes, d.hash(c))
+		}
+	}
+	return d.hasher()(n, childHashes)
+}
+
+// diff compares a and b, appending any changes found to changes. path is the
+// location of a and b relative to the roots originally passed to Diff.
+func (d *Differ) diff(a, b drill.Node, path string, changes *[]Change) {
+	if a == nil && b == nil {
+		return
+	}
+	if a == nil {
+		*changes = append(*changes, Change{Op: Insert, Path: path, New: b})
+		return
+	}
+	if b == nil {
+		*changes = append(*changes, Change{Op: Delete, Path: path, Old: a})
+		return
+	}
+	if bytes.Equal(d.hash(a), d.hash(b)) {
+		return
+	}
+	// A node may implement UnorderedBranch or OrderedBranch yet have no
+	// children at all, as a leaf value does when it is wrapped to satisfy an
+	// interface it doesn't conceptually implement. Such a node has nothing to
+	// recurse into, so it falls through to the fragment comparison below.
+	if ua, ok := a.(drill.UnorderedBranch); ok {
+		if ub, ok := b.(drill.UnorderedBranch); ok {
+			ac, bc := ua.UnorderedChildren(), ub.UnorderedChildren()
+			if len(ac) > 0 || len(bc) > 0 {
+				d.diffUnorderedChildren(ac, bc, path, changes)
+				return
+			}
+		}
+	}
+	if oa, ok := a.(drill.OrderedBranch); ok {
+		if ob, ok := b.(drill.OrderedBranch); ok {
+			if oa.Len() > 0 || ob.Len() > 0 {
+				d.diffOrdered(oa, ob, path, changes)
+				return
+			}
+		}
+	}
+	if fragment(a) != fragment(b) {
+		*changes = append(*changes, Change{Op: Modify, Path: path, Old: a, New: b})
+	}
+}
+
+// diffUnorderedChildren matches children of ac and bc by name, recursing into
+// each pair, and emitting Insert or Delete for names that appear on only one
+// side.
+func (d *Differ) diffUnorderedChildren(ac, bc map[string]drill.Node, path string, changes *[]Change) {
+	names := make(map[string]bool, len(ac)+len(bc))
+	for name := range ac {
+		names[name] = true
+	}
+	for name := range bc {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		d.diff(ac[name], bc[name], joinPath(path, name), changes)
+	}
+}
+
+// diffOrdered aligns the children of a and b with an LCS over their content
+// hashes, emitting Insert and Delete for the unaligned children, and
+// reclassifying a matched Insert/Delete pair as a Move when the hashes are
+// equal.
+func (d *Differ) diffOrdered(a, b drill.OrderedBranch, path string, changes *[]Change) {
+	ac := a.OrderedChildren()
+	bc := b.OrderedChildren()
+	ah := make([][]byte, len(ac))
+	for i, c := range ac {
+		ah[i] = d.hash(c)
+	}
+	bh := make([][]byte, len(bc))
+	for i, c := range bc {
+		bh[i] = d.hash(c)
+	}
+
+	usedA := make([]bool, len(ac))
+	usedB := make([]bool, len(bc))
+	for _, p := range lcsPairs(ah, bh) {
+		usedA[p[0]] = true
+		usedB[p[1]] = true
+	}
+
+	// A deletion whose hash matches an insertion is really a move.
+	movedTo := make(map[int]int) // a index -> b index
+	movedFrom := make(map[int]bool)
+	for ai, used := range usedA {
+		if used {
+			continue
+		}
+		for bi, used := range usedB {
+			if used || movedFrom[bi] {
+				continue
+			}
+			if bytes.Equal(ah[ai], bh[bi]) {
+				movedTo[ai] = bi
+				movedFrom[bi] = true
+				usedB[bi] = true
+				break
+			}
+		}
+	}
+
+	for ai := range ac {
+		if usedA[ai] {
+			continue
+		}
+		if bi, ok := movedTo[ai]; ok {
+			*changes = append(*changes, Change{
+				Op:   Move,
+				Path: joinIndex(path, bi),
+				From: joinIndex(path, ai),
+				Old:  ac[ai],
+				New:  bc[bi],
+			})
+			continue
+		}
+		*changes = append(*changes, Change{Op: Delete, Path: joinIndex(path, ai), Old: ac[ai]})
+	}
+	for bi := range bc {
+		if usedB[bi] || movedFrom[bi] {
+			continue
+		}
+		*changes = append(*changes, Change{Op: Insert, Path: joinIndex(path, bi), New: bc[bi]})
+	}
+}
+
+// lcsPairs returns index pairs (i, j) such that ah[i] equals bh[j], forming
+// the longest common subsequence of ah and bh, in increasing order of both i
+// and j.
+func lcsPairs(ah, bh [][]byte) [][2]int {
+	n, m := len(ah), len(bh)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case bytes.Equal(ah[i], bh[j]):
+				dp[i][j] = dp[i+1][j+1] + 1
+			case dp[i+1][j] >= dp[i][j+1]:
+				dp[i][j] = dp[i+1][j]
+			default:
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+	var pairs [][2]int
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case bytes.Equal(ah[i], bh[j]):
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// joinPath appends name to path, separated by a slash.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "/" + name
+}
+
+// joinIndex appends the ordered index i to path, separated by a slash.
+func joinIndex(path string, i int) string {
+	return joinPath(path, fmt.Sprintf("[%d]", i))
+}
+
+// Apply replays changes against root, which must implement drill.MutableNode
+// at every path referenced by a change. An Insert into an unordered branch
+// uses SetChild; an Insert into an ordered branch uses AppendChild, since
+// drill.MutableNode has no means of inserting at a specific index. A Delete,
+// Modify, or Move targeting an ordered index is not supported, since
+// drill.MutableNode likewise has no means of removing or replacing a
+// specific ordered child, and returns an error.
+func Apply(root drill.MutableNode, changes []Change) error {
+	for _, c := range changes {
+		if err := apply(root, c); err != nil {
+			return fmt.Errorf("diff: apply %s %q: %w", c.Op, c.Path, err)
+		}
+	}
+	return nil
+}
+
+func apply(root drill.MutableNode, c Change) error {
+	parentPath, name, ordered := splitPath(c.Path)
+	parentNode, err := drill.Resolve(root, parentPath)
+	if err != nil {
+		return err
+	}
+	parent, ok := parentNode.(drill.MutableNode)
+	if !ok {
+		return fmt.Errorf("node at %q is not a MutableNode", parentPath)
+	}
+	switch c.Op {
+	case Insert:
+		if ordered {
+			return parent.AppendChild(c.New)
+		}
+		return parent.SetChild(name, c.New)
+	case Delete:
+		if ordered {
+			return fmt.Errorf("cannot delete ordered child at index %s", name)
+		}
+		return parent.RemoveChild(name)
+	case Modify:
+		if ordered {
+			return fmt.Errorf("cannot modify ordered child at index %s", name)
+		}
+		return parent.SetChild(name, c.New)
+	case Move:
+		if ordered {
+			return fmt.Errorf("cannot move ordered child to index %s", name)
+		}
+		return parent.SetChild(name, c.New)
+	default:
+		return fmt.Errorf("unknown op %v", c.Op)
+	}
+}
+
+// splitPath splits a Change.Path into its parent path and final segment,
+// reporting whether the final segment is an ordered index.
+func splitPath(path string) (parent, name string, ordered bool) {
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		parent, name = path[:i], path[i+1:]
+	} else {
+		name = path
+	}
+	ordered = strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]")
+	return parent, name, ordered
+}
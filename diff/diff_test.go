@@ -0,0 +1,45 @@
+package diff_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/anaminus/drill"
+	"github.com/anaminus/drill/diff"
+	"github.com/anaminus/drill/filesys/markdown"
+)
+
+func newDoc(t *testing.T, s string) drill.Node {
+	t.Helper()
+	fsys := fstest.MapFS{"doc.md": {Data: []byte(s)}}
+	return markdown.NewHandler()(fsys, "doc.md")
+}
+
+func TestDiffFrontmatterScalarField(t *testing.T) {
+	a := newDoc(t, "---\ntitle: A\n---\n# Heading\nbody\n")
+	b := newDoc(t, "---\ntitle: B\n---\n# Heading\nbody\n")
+	changes := diff.Diff(a, b)
+	var found bool
+	for _, c := range changes {
+		if c.Op == diff.Modify && c.Path == "frontmatter/title" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Diff(a, b) = %v, want a Modify at frontmatter/title", changes)
+	}
+}
+
+func TestApplyMoveOrderedReturnsError(t *testing.T) {
+	n := markdown.NewHandler()(fstest.MapFS{
+		"doc.md": {Data: []byte("# A\nbody a\n\n# B\nbody b\n")},
+	}, "doc.md")
+	root, ok := n.(drill.MutableNode)
+	if !ok {
+		t.Fatalf("NewHandler did not return a MutableNode")
+	}
+	changes := []diff.Change{{Op: diff.Move, Path: "[0]", From: "[1]"}}
+	if err := diff.Apply(root, changes); err == nil {
+		t.Fatalf("Apply(Move at ordered index) = nil error, want an error")
+	}
+}
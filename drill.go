@@ -111,31 +111,6 @@ func Query(n Node, queries ...interface{}) Node {
 	return n
 }
 
-func descendants(d *[]Node, n OrderedBranch) {
-	for _, child := range n.OrderedChildren() {
-		if child == nil {
-			continue
-		}
-		*d = append(*d, child)
-		if o, ok := n.(OrderedBranch); ok {
-			descendants(d, o)
-		}
-	}
-}
-
-// Descendants returns a list of all the descendants of the node. If a node does
-// not implement OrderedBranch, then its children are skipped.
-func Descendants(n Node) []Node {
-	d := []Node{}
-	if n == nil {
-		return d
-	}
-	if o, ok := n.(OrderedBranch); ok {
-		descendants(&d, o)
-	}
-	return d
-}
-
 // Index returns i such that, if it is less than 0, it wraps around to len, so
 // that -1 returns the index of the last node, and so on. Returns a value less
 // than 0 if i is out of bounds, or if len is less than or equal to 0.
@@ -0,0 +1,309 @@
+package filesys
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anaminus/drill"
+)
+
+// NewZipHandler returns a HandlerFunc that opens a matched file as a zip
+// archive, exposing its contents as a new FS whose handlers are handlers, so
+// that a file within the archive can itself be drilled into.
+func NewZipHandler(handlers Handlers) HandlerFunc {
+	return func(fsys fs.FS, name string) drill.Node {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil
+		}
+		return &FS{FS: zr, handlers: handlers}
+	}
+}
+
+// decompressor wraps r with a streaming decompression format.
+type decompressor func(r io.Reader) (io.Reader, error)
+
+// NewTarHandler returns a HandlerFunc that opens a matched file as a tar
+// archive, exposing its contents as a new FS whose handlers are handlers.
+// Since archive/tar only supports forward streaming, entries are
+// materialized into memory the first time the file is opened.
+func NewTarHandler(handlers Handlers) HandlerFunc {
+	return newTarHandler(handlers, func(r io.Reader) (io.Reader, error) { return r, nil })
+}
+
+// NewTarGzipHandler is like NewTarHandler, but first decompresses the matched
+// file with gzip, for archives such as *.tar.gz or *.tgz.
+func NewTarGzipHandler(handlers Handlers) HandlerFunc {
+	return newTarHandler(handlers, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) })
+}
+
+// NewTarBzip2Handler is like NewTarHandler, but first decompresses the
+// matched file with bzip2, for archives such as *.tar.bz2 or *.tbz2.
+func NewTarBzip2Handler(handlers Handlers) HandlerFunc {
+	return newTarHandler(handlers, func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil })
+}
+
+func newTarHandler(handlers Handlers, decompress decompressor) HandlerFunc {
+	return func(fsys fs.FS, name string) drill.Node {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		r, err := decompress(f)
+		if err != nil {
+			return nil
+		}
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return nil
+		}
+		mem, err := indexTar(buf)
+		if err != nil {
+			return nil
+		}
+		return &FS{FS: mem, handlers: handlers}
+	}
+}
+
+// indexTar reads the tar archive in buf, recording the offset and size of
+// each regular file entry. Since archive/tar only supports forward
+// streaming, buf must hold the whole archive; indexTar does not copy an
+// entry's content out of buf, and memFS.Open reads it in place.
+func indexTar(buf []byte) (*memFS, error) {
+	mem := newMemFS(buf)
+	br := bytes.NewReader(buf)
+	tr := tar.NewReader(br)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		offset := int64(len(buf)) - int64(br.Len())
+		mem.put(hdr.Name, offset, hdr.Size)
+	}
+	return mem, nil
+}
+
+// Recursive returns a copy of hs with handlers for *.zip, *.tar, *.tar.gz,
+// *.tgz, *.tar.bz2, and *.tbz2 prepended, each configured to expose its
+// contents using hs.Recursive(maxDepth-1), so that an archive nested within
+// another archive can also be drilled into. maxDepth limits how many levels
+// of nested archives are recognized this way; beyond that depth, a nested
+// archive is left as an ordinary, undrillable file. If maxDepth is 0 or
+// less, hs itself is returned, with no archive handlers added.
+func (hs Handlers) Recursive(maxDepth int) Handlers {
+	if maxDepth <= 0 {
+		return hs
+	}
+	inner := hs.Recursive(maxDepth - 1)
+	archive := Handlers{
+		{Pattern: "*.zip", Func: NewZipHandler(inner)},
+		{Pattern: "*.tar", Func: NewTarHandler(inner)},
+		{Pattern: "*.tar.gz", Func: NewTarGzipHandler(inner)},
+		{Pattern: "*.tgz", Func: NewTarGzipHandler(inner)},
+		{Pattern: "*.tar.bz2", Func: NewTarBzip2Handler(inner)},
+		{Pattern: "*.tbz2", Func: NewTarBzip2Handler(inner)},
+	}
+	result := make(Handlers, 0, len(archive)+len(hs))
+	result = append(result, archive...)
+	result = append(result, hs...)
+	return result
+}
+
+// memFS is a minimal in-memory fs.FS used to hold the materialized contents
+// of an archive that does not support random access. A file's content is not
+// copied: each call to Open returns a reader over its byte range within buf.
+type memFS struct {
+	buf   []byte
+	files map[string]tarEntry
+}
+
+// tarEntry records where an entry's content lies within memFS.buf.
+type tarEntry struct {
+	offset int64
+	size   int64
+}
+
+func newMemFS(buf []byte) *memFS {
+	return &memFS{buf: buf, files: map[string]tarEntry{}}
+}
+
+// put records the offset and size of the entry at the cleaned,
+// slash-separated name, without materializing its content.
+func (m *memFS) put(name string, offset, size int64) {
+	m.files[path.Clean(name)] = tarEntry{offset: offset, size: size}
+}
+
+// dirPrefix returns the prefix that entries within name would have, with name
+// itself normalized to ".".
+func dirPrefix(name string) string {
+	if name == "." {
+		return ""
+	}
+	return name + "/"
+}
+
+func (m *memFS) isDir(name string) bool {
+	if name == "." {
+		return true
+	}
+	prefix := dirPrefix(name)
+	for p := range m.files {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	name = path.Clean(name)
+	if e, ok := m.files[name]; ok {
+		data := m.buf[e.offset : e.offset+e.size]
+		return &memFile{info: memFileInfo{name: path.Base(name), size: e.size}, r: bytes.NewReader(data)}, nil
+	}
+	if m.isDir(name) {
+		return &memDir{fs: m, name: name}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !m.isDir(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	prefix := dirPrefix(path.Clean(name))
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	for p, e := range m.files {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(p, prefix)
+		base := rest
+		isDir := false
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			base = rest[:i]
+			isDir = true
+		}
+		if seen[base] {
+			continue
+		}
+		seen[base] = true
+		if isDir {
+			entries = append(entries, memDirEntry{name: base})
+		} else {
+			entries = append(entries, memFileEntry{memFileInfo{name: base, size: e.size}})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// memFile is a regular file held in memory.
+type memFile struct {
+	info memFileInfo
+	r    *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Read(p []byte) (int, error) { return f.r.Read(p) }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is a directory synthesized from the paths of memFS's files.
+type memDir struct {
+	fs   *memFS
+	name string
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) {
+	return memDirInfo{name: path.Base(d.name)}, nil
+}
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) Close() error { return nil }
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries, err := d.fs.ReadDir(d.name)
+	if err != nil || n <= 0 {
+		return entries, err
+	}
+	if n > len(entries) {
+		n = len(entries)
+	}
+	return entries[:n], nil
+}
+
+// memFileInfo describes a regular file held in memory.
+type memFileInfo struct {
+	name string
+	size int64
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return 0444 }
+func (i memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() interface{}   { return nil }
+
+// memDirInfo describes a directory synthesized by memFS.
+type memDirInfo struct {
+	name string
+}
+
+func (i memDirInfo) Name() string       { return i.name }
+func (i memDirInfo) Size() int64        { return 0 }
+func (i memDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (i memDirInfo) ModTime() time.Time { return time.Time{} }
+func (i memDirInfo) IsDir() bool        { return true }
+func (i memDirInfo) Sys() interface{}   { return nil }
+
+// memFileEntry adapts memFileInfo to fs.DirEntry.
+type memFileEntry struct {
+	memFileInfo
+}
+
+func (e memFileEntry) Type() fs.FileMode          { return e.memFileInfo.Mode() }
+func (e memFileEntry) Info() (fs.FileInfo, error) { return e.memFileInfo, nil }
+
+// memDirEntry adapts a directory name to fs.DirEntry.
+type memDirEntry struct {
+	name string
+}
+
+func (e memDirEntry) Name() string               { return e.name }
+func (e memDirEntry) IsDir() bool                { return true }
+func (e memDirEntry) Type() fs.FileMode          { return fs.ModeDir }
+func (e memDirEntry) Info() (fs.FileInfo, error) { return memDirInfo{name: e.name}, nil }
@@ -0,0 +1,59 @@
+package filesys
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"testing"
+)
+
+// buildTar writes files as a tar archive, in map iteration order, returning
+// the encoded bytes.
+func buildTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, data := range files {
+		hdr := &tar.Header{Name: name, Size: int64(len(data)), Mode: 0644}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write([]byte(data)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestIndexTarMaterializesOnOpen(t *testing.T) {
+	buf := buildTar(t, map[string]string{
+		"a.txt": "hello a",
+		"b.txt": "hello b",
+	})
+	mem, err := indexTar(buf)
+	if err != nil {
+		t.Fatalf("indexTar: %v", err)
+	}
+	for name, e := range mem.files {
+		if e.size == 0 {
+			t.Fatalf("entry %q has size 0", name)
+		}
+	}
+	got, err := fs.ReadFile(mem, "a.txt")
+	if err != nil {
+		t.Fatalf("ReadFile a.txt: %v", err)
+	}
+	if string(got) != "hello a" {
+		t.Fatalf("a.txt content = %q, want %q", got, "hello a")
+	}
+	got, err = fs.ReadFile(mem, "b.txt")
+	if err != nil {
+		t.Fatalf("ReadFile b.txt: %v", err)
+	}
+	if string(got) != "hello b" {
+		t.Fatalf("b.txt content = %q, want %q", got, "hello b")
+	}
+}
@@ -18,9 +18,15 @@ import (
 // In order to descend into a file, the file must have an extension that matches
 // one of these handlers (according Handlers.Match). If there is a match, the
 // handler is called, returning the node returned by the handler.
+//
+// FS implements drill.MutableNode and drill.Writable. Modifications are
+// staged until Flush is called, at which point they are written back through
+// the wrapped fs.FS, which must additionally implement WriteFS.
 type FS struct {
 	fs.FS
 	handlers Handlers
+	pending  []change
+	content  *string
 }
 
 // NewFS returns an FS that wraps fsys, and includes a number of handlers.
@@ -115,9 +121,9 @@ func (f *FS) OrderedChildren() []drill.Node {
 		return nil
 	}
 	nodes := make([]drill.Node, 0, len(subs))
-	for i, entry := range subs {
+	for _, entry := range subs {
 		if sub, err := fsys.Sub(entry.Name()); err == nil {
-			nodes[i] = &FS{FS: sub}
+			nodes = append(nodes, &FS{FS: sub, handlers: f.handlers})
 		}
 	}
 	return nodes
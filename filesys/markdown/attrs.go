@@ -0,0 +1,64 @@
+package markdown
+
+import (
+	"fmt"
+
+	"github.com/anaminus/drill"
+	"github.com/yuin/goldmark/ast"
+)
+
+// attrsPseudoName is the name under which a Section's heading attributes
+// (such as {#id .class key=val}) are exposed as an unordered child.
+const attrsPseudoName = "attrs"
+
+// attrsNode exposes the attributes of an ast.Node as unordered leaf children.
+type attrsNode struct {
+	node ast.Node
+}
+
+// Fragment returns an empty string, since attrsNode has no fragment of its
+// own.
+func (n attrsNode) Fragment() string {
+	return ""
+}
+
+// UnorderedChild returns the attribute named name.
+func (n attrsNode) UnorderedChild(name string) drill.Node {
+	v, ok := n.node.AttributeString(name)
+	if !ok {
+		return nil
+	}
+	return attrNode{value: attrString(v)}
+}
+
+// UnorderedChildren returns every attribute of the node.
+func (n attrsNode) UnorderedChildren() map[string]drill.Node {
+	attrs := n.node.Attributes()
+	children := make(map[string]drill.Node, len(attrs))
+	for _, attr := range attrs {
+		children[string(attr.Name)] = attrNode{value: attrString(attr.Value)}
+	}
+	return children
+}
+
+// attrNode is a leaf Node wrapping the value of a single attribute.
+type attrNode struct {
+	value string
+}
+
+// Fragment returns the attribute value.
+func (n attrNode) Fragment() string {
+	return n.value
+}
+
+// attrString renders an attribute value, as produced by goldmark, as a string.
+func attrString(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,30 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/anaminus/drill"
+)
+
+// TestHeadingAttrs exercises the "attrs" pseudo-child of a Section, which
+// requires goldmark's attribute parsing to be enabled for {#id .class
+// key=val} syntax to be recognized rather than left as literal heading text.
+func TestHeadingAttrs(t *testing.T) {
+	// A heading's "id" attribute, if present, becomes its Section's Name,
+	// taking priority over the heading text.
+	node := newTestNode(t, "# Title {#intro key=val}\nbody\n")
+	section := node.Descend("intro")
+	if section == nil {
+		t.Fatalf("could not descend to intro")
+	}
+	attrs := drill.Descend(section, "attrs")
+	if attrs == nil {
+		t.Fatalf("attrs pseudo-child not found")
+	}
+	if got := attrs.(drill.UnorderedBranch).UnorderedChild("id"); got == nil || got.Fragment() != "intro" {
+		t.Fatalf("attrs id = %v, want %q", got, "intro")
+	}
+	if got := attrs.(drill.UnorderedBranch).UnorderedChild("key"); got == nil || got.Fragment() != "val" {
+		t.Fatalf("attrs key = %v, want %q", got, "val")
+	}
+}
@@ -0,0 +1,200 @@
+package markdown
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/anaminus/drill"
+	"github.com/yuin/goldmark"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontmatterFormat identifies the serialization format of a document's
+// frontmatter block.
+type FrontmatterFormat int
+
+const (
+	// FormatYAML parses frontmatter as YAML. This is the default.
+	FormatYAML FrontmatterFormat = iota
+	// FormatTOML parses frontmatter as TOML.
+	FormatTOML
+	// FormatJSON parses frontmatter as JSON.
+	FormatJSON
+)
+
+// handlerConfig accumulates the options passed to NewHandler.
+type handlerConfig struct {
+	goldmark          []goldmark.Option
+	frontmatterName   string
+	frontmatterFormat FrontmatterFormat
+}
+
+// HandlerOption configures a handler returned by NewHandler.
+type HandlerOption func(*handlerConfig)
+
+// WithGoldmark adds options used to configure the goldmark parser and
+// renderer used to process each file.
+func WithGoldmark(options ...goldmark.Option) HandlerOption {
+	return func(c *handlerConfig) {
+		c.goldmark = append(c.goldmark, options...)
+	}
+}
+
+// WithFrontmatterFormat sets the serialization format used to parse a
+// document's frontmatter block. Defaults to FormatYAML.
+func WithFrontmatterFormat(format FrontmatterFormat) HandlerOption {
+	return func(c *handlerConfig) {
+		c.frontmatterFormat = format
+	}
+}
+
+// WithFrontmatterName sets the name under which a document's frontmatter is
+// exposed as an unordered child of the document's root Node. Defaults to
+// "frontmatter". A name of "" disables frontmatter recognition.
+func WithFrontmatterName(name string) HandlerOption {
+	return func(c *handlerConfig) {
+		c.frontmatterName = name
+	}
+}
+
+// firstLine splits s at the first newline, returning the line, without the
+// newline, and the remainder. ok is false if s is empty.
+func firstLine(s []byte) (line string, rest []byte, ok bool) {
+	if len(s) == 0 {
+		return "", nil, false
+	}
+	if i := bytes.IndexByte(s, '\n'); i >= 0 {
+		return string(s[:i]), s[i+1:], true
+	}
+	return string(s), nil, true
+}
+
+// splitFrontmatter splits a leading frontmatter block from b. The block is
+// delimited by a line consisting solely of "---" or "+++", repeated to close
+// the block. Returns a nil block, and b unchanged, if b does not begin with a
+// recognized delimiter, or the delimiter is never closed.
+func splitFrontmatter(b []byte) (block []byte, body []byte) {
+	first, rest, ok := firstLine(b)
+	if !ok {
+		return nil, b
+	}
+	delim := strings.TrimRight(first, "\r")
+	if delim != "---" && delim != "+++" {
+		return nil, b
+	}
+	var buf bytes.Buffer
+	for {
+		line, next, ok := firstLine(rest)
+		if !ok {
+			return nil, b
+		}
+		if strings.TrimRight(line, "\r") == delim {
+			return buf.Bytes(), next
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		rest = next
+	}
+}
+
+// parseFrontmatter decodes block according to format.
+func parseFrontmatter(block []byte, format FrontmatterFormat) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	switch format {
+	case FormatTOML:
+		if _, err := toml.Decode(string(block), &data); err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		if err := json.Unmarshal(block, &data); err != nil {
+			return nil, err
+		}
+	default:
+		if err := yaml.Unmarshal(block, &data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// frontmatterNode exposes a document's parsed frontmatter block as a
+// drill.Node: Fragment returns the raw block, and its fields are exposed as
+// unordered children.
+type frontmatterNode struct {
+	raw  []byte
+	data map[string]interface{}
+}
+
+// Fragment returns the raw frontmatter block, excluding its delimiters.
+func (n frontmatterNode) Fragment() string {
+	return string(n.raw)
+}
+
+// UnorderedChild returns the top-level frontmatter field named name.
+func (n frontmatterNode) UnorderedChild(name string) drill.Node {
+	v, ok := n.data[name]
+	if !ok {
+		return nil
+	}
+	return fieldNode{value: v}
+}
+
+// UnorderedChildren returns every top-level frontmatter field.
+func (n frontmatterNode) UnorderedChildren() map[string]drill.Node {
+	children := make(map[string]drill.Node, len(n.data))
+	for name, v := range n.data {
+		children[name] = fieldNode{value: v}
+	}
+	return children
+}
+
+// fieldNode exposes a single value decoded from frontmatter. A scalar value
+// is rendered as its fragment; a map value can additionally be descended
+// into by key.
+type fieldNode struct {
+	value interface{}
+}
+
+// Fragment renders the value as text.
+func (n fieldNode) Fragment() string {
+	return fmt.Sprint(n.value)
+}
+
+// UnorderedChild returns the field named name, if the value is a map.
+func (n fieldNode) UnorderedChild(name string) drill.Node {
+	v, ok := asStringMap(n.value)[name]
+	if !ok {
+		return nil
+	}
+	return fieldNode{value: v}
+}
+
+// UnorderedChildren returns every field of the value, if it is a map.
+func (n fieldNode) UnorderedChildren() map[string]drill.Node {
+	m := asStringMap(n.value)
+	children := make(map[string]drill.Node, len(m))
+	for name, v := range m {
+		children[name] = fieldNode{value: v}
+	}
+	return children
+}
+
+// asStringMap normalizes the map types produced by the supported decoders
+// into map[string]interface{}. Returns nil if v is not a map.
+func asStringMap(v interface{}) map[string]interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(m))
+		for k, val := range m {
+			out[fmt.Sprint(k)] = val
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -32,14 +32,31 @@ import (
 // precedes the end of the section or the first sub-heading, is contained within
 // an "orphaned" section. The name of this section is an empty string, and will
 // be the first child section.
-func NewHandler(options ...goldmark.Option) filesys.HandlerFunc {
+//
+// If the file begins with a frontmatter block, delimited by a line of "---"
+// or "+++", it is parsed according to WithFrontmatterFormat and exposed as an
+// unordered child of the root Node, under the name given by
+// WithFrontmatterName (by default, "frontmatter"). A heading's attributes
+// (for example, {#id .class key=val}) are likewise exposed as an unordered
+// child of its Section, under the pseudo-name "attrs".
+func NewHandler(options ...HandlerOption) filesys.HandlerFunc {
+	cfg := handlerConfig{frontmatterName: "frontmatter"}
+	for _, opt := range options {
+		opt(&cfg)
+	}
 	return func(fsys fs.FS, name string) drill.Node {
 		b, err := fs.ReadFile(fsys, name)
 		if err != nil {
 			return nil
 		}
-		options = append(options,
+		var fm []byte
+		body := b
+		if cfg.frontmatterName != "" {
+			fm, body = splitFrontmatter(b)
+		}
+		goldmarkOptions := append(append([]goldmark.Option{}, cfg.goldmark...),
 			goldmark.WithParserOptions(
+				parser.WithAttribute(),
 				parser.WithASTTransformers(
 					util.Prioritized(NewSectionTransformer(), 2000),
 				),
@@ -50,31 +67,49 @@ func NewHandler(options ...goldmark.Option) filesys.HandlerFunc {
 				),
 			),
 		)
-		md := goldmark.New(options...)
-		parser := md.Parser()
-		root := parser.Parse(text.NewReader(b))
-		node := NewNode(root, b, md.Renderer())
+		md := goldmark.New(goldmarkOptions...)
+		p := md.Parser()
+		root := p.Parse(text.NewReader(body))
+		node := NewNode(root, body, p, md.Renderer())
+		if fm != nil {
+			if data, err := parseFrontmatter(fm, cfg.frontmatterFormat); err == nil {
+				node.frontmatterName = cfg.frontmatterName
+				node.frontmatterRaw = fm
+				node.frontmatterData = data
+			}
+		}
 		return node
 	}
 }
 
-// Node implements drill.Node.
+// Node implements drill.Node, drill.MutableNode, and drill.Writable.
 type Node struct {
 	root     *Node
 	section  ast.Node
 	source   []byte
+	parser   parser.Parser
 	renderer renderer.Renderer
+
+	// frontmatterName is the name under which the root Node exposes its
+	// frontmatter, or "" if the document has none.
+	frontmatterName string
+	frontmatterRaw  []byte
+	frontmatterData map[string]interface{}
 }
 
-// NewNode returns a Node that wraps the given ast.Node, source, and renderer.
-// root is assumed to be an ast.Document or a Section.
+// NewNode returns a Node that wraps the given ast.Node, source, parser, and
+// renderer. root is assumed to be an ast.Document or a Section. parser is
+// used to reparse the source when the node is modified through SetChild,
+// RemoveChild, AppendChild, or SetFragment; it may be nil if the node will
+// not be modified.
 //
 // The node created by NewNode is treated as the root. Nodes that derive from
 // the root will point back to the root.
-func NewNode(root ast.Node, source []byte, renderer renderer.Renderer) *Node {
+func NewNode(root ast.Node, source []byte, parser parser.Parser, renderer renderer.Renderer) *Node {
 	node := &Node{
 		section:  root,
 		source:   source,
+		parser:   parser,
 		renderer: renderer,
 	}
 	node.root = node
@@ -97,6 +132,12 @@ func (n *Node) Root() *Node {
 	return n.root
 }
 
+// isRoot reports whether n is the document root, as opposed to a Node derived
+// from it. Frontmatter is only exposed by the root.
+func (n *Node) isRoot() bool {
+	return n.section == n.Root().section
+}
+
 // Node returns the wrapped ast.Node.
 func (n *Node) Node() ast.Node {
 	return n.section
@@ -195,8 +236,21 @@ func (n *Node) OrderedChildren() []drill.Node {
 }
 
 // UnorderedChild returns a Node that wraps the unordered child Section whose
-// Name is equal to name.
+// Name is equal to name. If name is "attrs", the attributes of n's heading
+// are returned instead, if it has one; if n is the document root and name
+// matches its configured frontmatter name, the document's frontmatter is
+// returned instead, if it has any. These pseudo-children take precedence over
+// an actual child Section of the same name.
 func (n *Node) UnorderedChild(name string) drill.Node {
+	if name == attrsPseudoName {
+		if sec, ok := n.section.(*Section); ok && sec.Heading != nil {
+			return attrsNode{node: sec.Heading}
+		}
+		return nil
+	}
+	if n.frontmatterName != "" && name == n.frontmatterName && n.isRoot() {
+		return frontmatterNode{raw: n.frontmatterRaw, data: n.frontmatterData}
+	}
 	var section ast.Node
 	n.WalkChildSections(func(child *Section) bool {
 		if child.Name == name {
@@ -212,78 +266,60 @@ func (n *Node) UnorderedChild(name string) drill.Node {
 }
 
 // UnorderedChildren returns a map of names to Nodes that wrap each unordered
-// child Section.
+// child Section, along with any pseudo-children described by UnorderedChild.
 func (n *Node) UnorderedChildren() map[string]drill.Node {
 	sections := map[string]drill.Node{}
 	n.WalkChildSections(func(child *Section) bool {
 		sections[child.Name] = n.derive(child)
 		return false
 	})
+	if sec, ok := n.section.(*Section); ok && sec.Heading != nil {
+		sections[attrsPseudoName] = attrsNode{node: sec.Heading}
+	}
+	if n.frontmatterName != "" && n.frontmatterRaw != nil && n.isRoot() {
+		sections[n.frontmatterName] = frontmatterNode{raw: n.frontmatterRaw, data: n.frontmatterData}
+	}
 	return sections
 }
 
-// Descend recursively descends into the unordered child sections matching each
-// given name. Returns nil if a child could not be found at any point.
+// Descend recursively descends into the unordered children matching each
+// given name, as returned by UnorderedChild. Returns nil if a child could not
+// be found at any point. Once a step leaves a *Node (for example, by entering
+// "attrs" or a frontmatter field), the remaining names are resolved with
+// drill.Descend.
 func (n *Node) Descend(names ...string) drill.Node {
-	for _, name := range names {
-		var ok bool
-		n.WalkChildSections(func(section *Section) bool {
-			if section.Name != name {
-				return false
-			}
-			ok = true
-			n = n.derive(section)
-			return true
-		})
+	var cur drill.Node = n
+	for i, name := range names {
+		nd, ok := cur.(*Node)
 		if !ok {
-			return nil
+			return drill.Descend(cur, names[i:]...)
 		}
+		cur = nd.UnorderedChild(name)
 	}
-	return n
+	return cur
 }
 
 // Query recursively descends into the child nodes that match the given queries.
 // A query is either a string or an int. If an int, then the next node is
 // acquired using the OrderedChild method of the current node. If a string, then
 // the next node is acquired using the UnorderedChild method of the current
-// node. Returns nil if a child could not be found at any point.
+// node. Returns nil if a child could not be found at any point. Once a step
+// leaves a *Node, the remaining queries are resolved with drill.Query.
 func (n *Node) Query(queries ...interface{}) drill.Node {
-	for _, query := range queries {
+	var cur drill.Node = n
+	for i, query := range queries {
+		nd, ok := cur.(*Node)
+		if !ok {
+			return drill.Query(cur, queries[i:]...)
+		}
 		switch q := query.(type) {
 		case string:
-			var section ast.Node
-			n.WalkChildSections(func(child *Section) bool {
-				if child.Name == q {
-					section = child
-					return true
-				}
-				return false
-			})
-			if section == nil {
-				return nil
-			}
-			n = n.derive(section)
+			cur = nd.UnorderedChild(q)
 		case int:
-			if q = drill.Index(q, n.Len()); q < 0 {
-				return nil
-			}
-			var count int
-			var section ast.Node
-			n.WalkChildSections(func(child *Section) bool {
-				if count == q {
-					section = child
-					return true
-				}
-				count++
-				return false
-			})
-			if section == nil {
-				return nil
-			}
-			n = n.derive(section)
+			cur = nd.OrderedChild(q)
 		default:
 			return nil
 		}
 	}
-	return n
+	return cur
 }
@@ -0,0 +1,216 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/anaminus/drill"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// span returns the byte range [start, end) of source covered by n and its
+// descendants, as recorded by the Lines of each block. Returns (0, 0) if n
+// has no lines.
+func span(n ast.Node, source []byte) (start, end int) {
+	s, e := rawSpan(n)
+	if s < 0 {
+		return 0, 0
+	}
+	return s, e
+}
+
+// rawSpan is like span, but leaves start as -1 if n has no lines, so callers
+// can distinguish "nothing found" from a span starting at byte 0.
+func rawSpan(n ast.Node) (start, end int) {
+	start, end = -1, -1
+	var walk func(ast.Node)
+	walk = func(node ast.Node) {
+		// Inline nodes' Lines() panics; only block and document nodes carry
+		// a meaningful source range.
+		if node.Type() != ast.TypeInline {
+			if b, ok := node.(interface{ Lines() *text.Segments }); ok {
+				lines := b.Lines()
+				for i := 0; i < lines.Len(); i++ {
+					seg := lines.At(i)
+					if start < 0 || seg.Start < start {
+						start = seg.Start
+					}
+					if seg.Stop > end {
+						end = seg.Stop
+					}
+				}
+			}
+		}
+		for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+			walk(c)
+		}
+	}
+	walk(n)
+	return start, end
+}
+
+// sectionSpan is like span, but additionally includes the heading of n, if n
+// is a Section with one.
+func sectionSpan(n ast.Node, source []byte) (start, end int) {
+	start, end = rawSpan(n)
+	if sec, ok := n.(*Section); ok && sec.Heading != nil {
+		hs, he := rawSpan(sec.Heading)
+		if hs >= 0 {
+			// Heading.Lines covers only the heading's text, after its "#"
+			// marker, so back up to the start of the line to include it.
+			hs = lineStart(source, hs)
+			if start < 0 || hs < start {
+				start = hs
+			}
+		}
+		if he > end {
+			end = he
+		}
+	}
+	if start < 0 {
+		return 0, 0
+	}
+	return start, end
+}
+
+// lineStart returns the byte offset of the start of the source line
+// containing pos.
+func lineStart(source []byte, pos int) int {
+	if i := bytes.LastIndexByte(source[:pos], '\n'); i >= 0 {
+		return i + 1
+	}
+	return 0
+}
+
+// sectionPath returns the names of each Section from the root of the document
+// down to and including n, suitable for passing to Node.Descend.
+func sectionPath(n ast.Node) []string {
+	var names []string
+	for s := n; s != nil; s = s.Parent() {
+		if sec, ok := s.(*Section); ok {
+			names = append([]string{sec.Name}, names...)
+		}
+	}
+	return names
+}
+
+// markdownText returns the raw Markdown source represented by n, for use when
+// splicing n into another document. If n is a *Node, the original source span
+// of its section (including its own heading, if any) is used directly;
+// otherwise, n.Fragment() is assumed to already be Markdown text.
+func markdownText(n drill.Node) string {
+	if mn, ok := n.(*Node); ok {
+		start, end := sectionSpan(mn.section, mn.Root().source)
+		return string(mn.Root().source[start:end])
+	}
+	return n.Fragment()
+}
+
+// splice replaces the byte range [start, end) of the root source with repl,
+// reparses the resulting document, and updates n in place to refer to the
+// node corresponding to its former position in the new tree. Any other Node
+// derived from the same root is left referring to the stale tree.
+func (n *Node) splice(start, end int, repl string) error {
+	if n.parser == nil {
+		return errors.New("markdown: node has no parser, cannot be modified")
+	}
+	root := n.Root()
+	path := sectionPath(n.section)
+	var buf bytes.Buffer
+	buf.Write(root.source[:start])
+	buf.WriteString(repl)
+	buf.Write(root.source[end:])
+	newSource := buf.Bytes()
+	newDoc := n.parser.Parse(text.NewReader(newSource))
+	newRoot := NewNode(newDoc, newSource, n.parser, n.renderer)
+	resolved := newRoot.Descend(path...)
+	r, ok := resolved.(*Node)
+	if !ok || r == nil {
+		return fmt.Errorf("markdown: could not resolve %q after edit", strings.Join(path, "/"))
+	}
+	*n = *r
+	return nil
+}
+
+// SetChild replaces the child Section named name with content parsed from child,
+// inserting a new subsection at the end of the current section if one does
+// not already exist.
+func (n *Node) SetChild(name string, child drill.Node) error {
+	var target *Section
+	n.WalkChildSections(func(section *Section) bool {
+		if section.Name == name {
+			target = section
+			return true
+		}
+		return false
+	})
+	text := markdownText(child)
+	if target != nil {
+		start, end := sectionSpan(target, n.Root().source)
+		return n.splice(start, end, text)
+	}
+	_, end := span(n.section, n.Root().source)
+	return n.splice(end, end, "\n\n"+text)
+}
+
+// RemoveChild removes the child Section named name, along with its heading.
+// Returns an error if no such child exists.
+func (n *Node) RemoveChild(name string) error {
+	var target *Section
+	n.WalkChildSections(func(section *Section) bool {
+		if section.Name == name {
+			target = section
+			return true
+		}
+		return false
+	})
+	if target == nil {
+		return fmt.Errorf("markdown: no child named %q", name)
+	}
+	start, end := sectionSpan(target, n.Root().source)
+	return n.splice(start, end, "")
+}
+
+// AppendChild parses child as Markdown and appends it as a new subsection at
+// the end of the current section.
+func (n *Node) AppendChild(child drill.Node) error {
+	_, end := span(n.section, n.Root().source)
+	return n.splice(end, end, "\n\n"+markdownText(child))
+}
+
+// Flush satisfies drill.MutableNode. SetChild, RemoveChild, and AppendChild
+// take effect immediately, so Flush has nothing to do.
+func (n *Node) Flush() error {
+	return nil
+}
+
+// SetFragment replaces the Markdown source of n's section (and its heading,
+// if any) with s, reparsing the document to reflect the change.
+func (n *Node) SetFragment(s string) error {
+	start, end := sectionSpan(n.section, n.Root().source)
+	return n.splice(start, end, s)
+}
+
+// fragmentWriter buffers writes, replacing the fragment of node once closed.
+type fragmentWriter struct {
+	node *Node
+	buf  bytes.Buffer
+}
+
+func (w *fragmentWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *fragmentWriter) Close() error {
+	return w.node.SetFragment(w.buf.String())
+}
+
+// FragmentWriter returns a WriteCloser that, once closed, replaces the
+// Markdown source of n's section with the written content.
+func (n *Node) FragmentWriter() (io.WriteCloser, error) {
+	return &fragmentWriter{node: n}, nil
+}
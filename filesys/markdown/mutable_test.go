@@ -0,0 +1,94 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anaminus/drill"
+)
+
+// newTestNode parses s as the sole file of a Markdown document, returning the
+// root Node so mutation methods can be exercised directly.
+func newTestNode(t *testing.T, s string) *Node {
+	t.Helper()
+	fsys := fstest.MapFS{"doc.md": {Data: []byte(s)}}
+	n := NewHandler()(fsys, "doc.md")
+	node, ok := n.(*Node)
+	if !ok {
+		t.Fatalf("NewHandler did not return a *Node")
+	}
+	return node
+}
+
+// rawMarkdown is a drill.Node whose Fragment is literal Markdown source, for
+// use as the argument to AppendChild/SetChild in tests.
+type rawMarkdown string
+
+func (n rawMarkdown) Fragment() string { return string(n) }
+
+// TestMutableRoundTrip exercises SetFragment, RemoveChild, and AppendChild on
+// documents containing ordinary headings and prose, which are represented by
+// inline nodes that must not be visited by span's Lines() walk.
+func TestMutableRoundTrip(t *testing.T) {
+	const source = "# Title\nintro text\n\n# Foo\nfoo body\n\n# Bar\nbar body\n"
+
+	t.Run("RemoveChild", func(t *testing.T) {
+		node := newTestNode(t, source)
+		if err := node.RemoveChild("Bar"); err != nil {
+			t.Fatalf("RemoveChild: %v", err)
+		}
+		if child := node.Descend("Bar"); child != nil {
+			t.Fatalf("Bar still present after RemoveChild")
+		}
+		child := node.Descend("Foo")
+		if child == nil {
+			t.Fatalf("Foo removed along with Bar")
+		}
+		if got := child.(*Node).Fragment(); !strings.Contains(got, "foo body") {
+			t.Fatalf("Foo fragment = %q, want to contain %q", got, "foo body")
+		}
+		if got := string(node.Root().source); strings.Contains(got, "#\n") || strings.Contains(got, "# \n") {
+			t.Fatalf("source after RemoveChild left a stray heading marker: %q", got)
+		}
+	})
+
+	t.Run("RemoveChild empty heading", func(t *testing.T) {
+		// Back-to-back headings: Foo has no body content, so its section's
+		// own span is empty. Removing Bar, which directly follows it, must
+		// leave Foo and the content before it in place.
+		node := newTestNode(t, "# Title\nintro\n\n# Foo\n# Bar\nbar body\n")
+		if err := node.RemoveChild("Bar"); err != nil {
+			t.Fatalf("RemoveChild: %v", err)
+		}
+		if child := node.Descend("Foo"); child == nil {
+			t.Fatalf("Foo removed along with Bar")
+		}
+		if !strings.Contains(node.Fragment(), "intro") {
+			t.Fatalf("content preceding Foo was destroyed: %q", node.Fragment())
+		}
+	})
+
+	t.Run("SetFragment", func(t *testing.T) {
+		node := newTestNode(t, source)
+		foo := node.Descend("Foo").(*Node)
+		if err := foo.SetFragment("# Foo\nnew body\n"); err != nil {
+			t.Fatalf("SetFragment: %v", err)
+		}
+		if !strings.Contains(foo.Fragment(), "new body") {
+			t.Fatalf("SetFragment did not take effect: %q", foo.Fragment())
+		}
+	})
+
+	t.Run("AppendChild", func(t *testing.T) {
+		node := newTestNode(t, source)
+		if err := node.AppendChild(rawMarkdown("# Baz\nbaz body\n")); err != nil {
+			t.Fatalf("AppendChild: %v", err)
+		}
+		if child := node.Descend("Baz"); child == nil {
+			t.Fatalf("Baz not present after AppendChild")
+		}
+	})
+}
+
+var _ drill.Node = rawMarkdown("")
@@ -0,0 +1,182 @@
+package filesys
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/anaminus/drill"
+)
+
+// WriteFS extends fs.FS with the ability to create and remove files and
+// directories. An fs.FS passed to NewFS that also implements WriteFS allows
+// the resulting FS to support drill.MutableNode and drill.Writable; Flush
+// writes pending modifications back through these methods.
+type WriteFS interface {
+	fs.FS
+	// Create opens the named file for writing, creating it if it does not
+	// exist and truncating it otherwise.
+	Create(name string) (io.WriteCloser, error)
+	// Remove removes the named file, or directory and its contents.
+	Remove(name string) error
+	// Mkdir creates the named directory.
+	Mkdir(name string) error
+}
+
+// changeKind identifies the kind of a pending change.
+type changeKind int
+
+const (
+	changeSet changeKind = iota
+	changeRemove
+	changeAppend
+)
+
+// change records a single pending modification to be applied by Flush.
+type change struct {
+	kind changeKind
+	name string
+	node drill.Node
+}
+
+// SetChild marks name to be created or replaced with the content of n the
+// next time Flush is called.
+func (f *FS) SetChild(name string, n drill.Node) error {
+	f.pending = append(f.pending, change{kind: changeSet, name: name, node: n})
+	return nil
+}
+
+// RemoveChild marks name to be removed the next time Flush is called.
+func (f *FS) RemoveChild(name string) error {
+	f.pending = append(f.pending, change{kind: changeRemove, name: name})
+	return nil
+}
+
+// AppendChild marks n to be created under a generated name the next time
+// Flush is called.
+func (f *FS) AppendChild(n drill.Node) error {
+	f.pending = append(f.pending, change{kind: changeAppend, node: n})
+	return nil
+}
+
+// SetFragment marks the file wrapped by f to be replaced with s the next time
+// Flush is called.
+func (f *FS) SetFragment(s string) error {
+	f.content = &s
+	return nil
+}
+
+// fragmentWriter buffers writes, replacing the fragment of fsys once closed.
+type fragmentWriter struct {
+	fsys *FS
+	buf  bytes.Buffer
+}
+
+func (w *fragmentWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *fragmentWriter) Close() error {
+	return w.fsys.SetFragment(w.buf.String())
+}
+
+// FragmentWriter returns a WriteCloser that, once closed, marks the file
+// wrapped by f to be replaced with the written content the next time Flush is
+// called.
+func (f *FS) FragmentWriter() (io.WriteCloser, error) {
+	return &fragmentWriter{fsys: f}, nil
+}
+
+// Flush writes pending modifications made through SetChild, RemoveChild,
+// AppendChild, and SetFragment back through the FS's underlying fs.FS.
+// Returns an error if the underlying fs.FS does not implement WriteFS.
+func (f *FS) Flush() error {
+	wfs, ok := f.FS.(WriteFS)
+	if !ok {
+		return fmt.Errorf("filesys: %T does not implement WriteFS", f.FS)
+	}
+	if f.content != nil {
+		if err := writeFragment(wfs, ".", *f.content); err != nil {
+			return err
+		}
+		f.content = nil
+	}
+	next := 0
+	for _, c := range f.pending {
+		switch c.kind {
+		case changeSet:
+			if err := writeNode(wfs, c.name, c.node); err != nil {
+				return err
+			}
+		case changeRemove:
+			if err := wfs.Remove(c.name); err != nil {
+				return err
+			}
+		case changeAppend:
+			name, n := nextName(wfs, next)
+			if err := writeNode(wfs, name, c.node); err != nil {
+				return err
+			}
+			next = n
+		}
+	}
+	f.pending = nil
+	return nil
+}
+
+// nextName returns the first name of the form "<i>" for i >= from that does
+// not already exist in wfs, along with the index following it.
+func nextName(wfs WriteFS, from int) (name string, next int) {
+	for i := from; ; i++ {
+		name = fmt.Sprintf("%d", i)
+		if _, err := fs.Stat(wfs, name); err != nil {
+			return name, i + 1
+		}
+	}
+}
+
+// writeNode writes n to name within wfs, recursing into directories for
+// branch nodes.
+func writeNode(wfs WriteFS, name string, n drill.Node) error {
+	if n == nil {
+		return nil
+	}
+	if o, ok := n.(drill.OrderedBranch); ok {
+		if err := wfs.Mkdir(name); err != nil {
+			return err
+		}
+		for i, child := range o.OrderedChildren() {
+			if err := writeNode(wfs, path.Join(name, fmt.Sprintf("%d", i)), child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if u, ok := n.(drill.UnorderedBranch); ok {
+		if err := wfs.Mkdir(name); err != nil {
+			return err
+		}
+		for childName, child := range u.UnorderedChildren() {
+			if err := writeNode(wfs, path.Join(name, childName), child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return writeFragment(wfs, name, n.Fragment())
+}
+
+// writeFragment writes content to name within wfs.
+func writeFragment(wfs WriteFS, name, content string) error {
+	w, err := wfs.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
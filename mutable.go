@@ -0,0 +1,37 @@
+package drill
+
+import "io"
+
+// MutableNode extends Node to allow its children to be modified.
+//
+// Modifications made through SetChild, RemoveChild, and AppendChild need not
+// take effect immediately against the node's underlying source; Flush commits
+// any pending modifications.
+type MutableNode interface {
+	Node
+	// SetChild sets the child named name to n, replacing any existing child
+	// of that name, or creating it if it does not exist. Returns an error if
+	// the node does not support children of that kind, or n could not be set.
+	SetChild(name string, n Node) error
+	// RemoveChild removes the child named name. Returns an error if no such
+	// child exists, or it could not be removed.
+	RemoveChild(name string) error
+	// AppendChild appends n as a new ordered child of the node. Returns an
+	// error if the node does not support ordered children, or n could not be
+	// appended.
+	AppendChild(n Node) error
+	// Flush commits any pending modifications made through SetChild,
+	// RemoveChild, or AppendChild back to the node's underlying source.
+	Flush() error
+}
+
+// Writable extends Node to allow the fragment produced by the node to be
+// replaced.
+type Writable interface {
+	Node
+	// SetFragment replaces the fragment of the node with s.
+	SetFragment(s string) error
+	// FragmentWriter returns a WriteCloser that, once closed, replaces the
+	// fragment of the node with the content written to it.
+	FragmentWriter() (w io.WriteCloser, err error)
+}
@@ -0,0 +1,185 @@
+package drill
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// sameNode reports whether a and b refer to the same underlying node. Since
+// implementations such as filesys.FS and markdown.Node allocate a new wrapper
+// on every call, pointer equality cannot be used to recognize that two
+// separately-produced Nodes denote the same child; reflect.DeepEqual compares
+// them structurally instead.
+func sameNode(a, b Node) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// resolveIndex returns the index of child among n's ordered children, or -1
+// if n does not implement OrderedBranch, or child could not be found among
+// them.
+func resolveIndex(n Node, child Node) int {
+	o, ok := n.(OrderedBranch)
+	if !ok {
+		return -1
+	}
+	for i, c := range o.OrderedChildren() {
+		if sameNode(c, child) {
+			return i
+		}
+	}
+	return -1
+}
+
+// PathElem is a single step recorded by Walk, identifying a node relative to
+// its parent.
+type PathElem struct {
+	// Name is the name used to reach Node via UnorderedChild, or an empty
+	// string if Node was reached via OrderedChild, or if it is the root.
+	Name string
+	// Index is the index of Node within its parent's ordered children. The
+	// Walk function resolves this even if Node was reached via
+	// UnorderedChild, so that Sibling does not need to search for it later.
+	// It is -1 if the parent does not implement OrderedBranch, Node could not
+	// be found among its ordered children, or it is the root. Paths built by
+	// a Walker instead leave Index as -1 for every step reached by name.
+	Index int
+	// Node is the node at this step.
+	Node Node
+}
+
+// Path is an ordered sequence of PathElems describing a descent from a root
+// Node, as returned by Walk. The first element always refers to the root
+// itself.
+type Path []PathElem
+
+// Parent returns the Path to the parent of the last element of p. Returns p
+// unchanged if p refers only to the root.
+func (p Path) Parent() Path {
+	if len(p) <= 1 {
+		return p
+	}
+	return p[:len(p)-1]
+}
+
+// Sibling returns the node offset positions away from the last element of p,
+// among the ordered children of its parent. Returns an error if p has no
+// parent, the parent does not implement OrderedBranch, or offset is out of
+// bounds.
+func (p Path) Sibling(offset int) (Node, error) {
+	if len(p) < 2 {
+		return nil, fmt.Errorf("drill: path has no parent")
+	}
+	parent := p[len(p)-2].Node
+	o, ok := parent.(OrderedBranch)
+	if !ok {
+		return nil, fmt.Errorf("drill: parent does not implement OrderedBranch")
+	}
+	last := p[len(p)-1]
+	index := last.Index
+	if index < 0 {
+		index = resolveIndex(parent, last.Node)
+		if index < 0 {
+			return nil, fmt.Errorf("drill: could not determine index of last path element")
+		}
+	}
+	i := index + offset
+	if i < 0 || i >= o.Len() {
+		return nil, fmt.Errorf("drill: sibling offset %d out of bounds", offset)
+	}
+	return o.OrderedChild(i), nil
+}
+
+// String renders p as a slash-delimited path, using "[i]" to represent a step
+// taken by ordered index. The root itself is not included.
+func (p Path) String() string {
+	if len(p) <= 1 {
+		return ""
+	}
+	parts := make([]string, 0, len(p)-1)
+	for _, elem := range p[1:] {
+		if elem.Name != "" {
+			parts = append(parts, elem.Name)
+		} else {
+			parts = append(parts, fmt.Sprintf("[%d]", elem.Index))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Walk recursively descends into the child nodes that match the given
+// queries, recording each step of the descent as a Path. A query is either a
+// string or an int, with the same meaning as in Query. Unlike Query, Walk
+// does not consult the Queryer interface, since a Queryer implementation may
+// not expose the intermediate steps of its descent.
+//
+// Returns an error if a child could not be found at any point.
+func Walk(root Node, queries ...interface{}) (Path, error) {
+	path := make(Path, 1, len(queries)+1)
+	path[0] = PathElem{Index: -1, Node: root}
+	n := root
+	for _, query := range queries {
+		if n == nil {
+			return nil, fmt.Errorf("drill: cannot descend into nil node")
+		}
+		switch q := query.(type) {
+		case string:
+			u, ok := n.(UnorderedBranch)
+			if !ok {
+				return nil, fmt.Errorf("drill: node does not implement UnorderedBranch")
+			}
+			child := u.UnorderedChild(q)
+			if child == nil {
+				return nil, fmt.Errorf("drill: no child named %q", q)
+			}
+			path = append(path, PathElem{Name: q, Index: resolveIndex(n, child), Node: child})
+			n = child
+		case int:
+			o, ok := n.(OrderedBranch)
+			if !ok {
+				return nil, fmt.Errorf("drill: node does not implement OrderedBranch")
+			}
+			i := Index(q, o.Len())
+			if i < 0 {
+				return nil, fmt.Errorf("drill: index %d out of bounds", q)
+			}
+			child := o.OrderedChild(i)
+			if child == nil {
+				return nil, fmt.Errorf("drill: no child at index %d", q)
+			}
+			path = append(path, PathElem{Index: i, Node: child})
+			n = child
+		default:
+			return nil, fmt.Errorf("drill: invalid query type %T", query)
+		}
+	}
+	return path, nil
+}
+
+// Resolve reparses a string produced by Path.String, descending from root to
+// find the node it refers to. Returns an error if the string is malformed, or
+// a child could not be found at any point.
+func Resolve(root Node, path string) (Node, error) {
+	if path == "" {
+		return root, nil
+	}
+	segments := strings.Split(path, "/")
+	queries := make([]interface{}, len(segments))
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "[") && strings.HasSuffix(seg, "]") {
+			idx, err := strconv.Atoi(seg[1 : len(seg)-1])
+			if err != nil {
+				return nil, fmt.Errorf("drill: invalid path segment %q: %w", seg, err)
+			}
+			queries[i] = idx
+		} else {
+			queries[i] = seg
+		}
+	}
+	p, err := Walk(root, queries...)
+	if err != nil {
+		return nil, err
+	}
+	return p[len(p)-1].Node, nil
+}
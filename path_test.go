@@ -0,0 +1,31 @@
+package drill_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/anaminus/drill"
+	"github.com/anaminus/drill/filesys/markdown"
+)
+
+func TestWalkResolvesIndexForNamedStep(t *testing.T) {
+	fsys := fstest.MapFS{
+		"doc.md": {Data: []byte("# Root\n\n## A\nbody a\n\n## B\nbody b\n\n## C\nbody c\n")},
+	}
+	root := markdown.NewHandler()(fsys, "doc.md")
+	p, err := drill.Walk(root, "Root", "B")
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	last := p[len(p)-1]
+	if last.Index != 1 {
+		t.Fatalf("Index of %q = %d, want 1", last.Name, last.Index)
+	}
+	sib, err := p.Sibling(1)
+	if err != nil {
+		t.Fatalf("Sibling: %v", err)
+	}
+	if sib == nil {
+		t.Fatalf("Sibling returned nil")
+	}
+}
@@ -0,0 +1,141 @@
+package drill
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// snapshot is the serialized form of a Node, used by Snapshot and Restore.
+type snapshot struct {
+	Fragment  string              `json:"fragment,omitempty"`
+	Ordered   []snapshot          `json:"ordered,omitempty"`
+	Unordered map[string]snapshot `json:"unordered,omitempty"`
+}
+
+// newSnapshot recursively captures the fragment and children of n.
+func newSnapshot(n Node) snapshot {
+	var s snapshot
+	if n == nil {
+		return s
+	}
+	s.Fragment = n.Fragment()
+	if o, ok := n.(OrderedBranch); ok {
+		children := o.OrderedChildren()
+		s.Ordered = make([]snapshot, len(children))
+		for i, child := range children {
+			s.Ordered[i] = newSnapshot(child)
+		}
+	}
+	if u, ok := n.(UnorderedBranch); ok {
+		children := u.UnorderedChildren()
+		s.Unordered = make(map[string]snapshot, len(children))
+		for name, child := range children {
+			s.Unordered[name] = newSnapshot(child)
+		}
+	}
+	return s
+}
+
+// restore builds the in-memory Node tree described by s.
+func (s snapshot) restore() *snapshotNode {
+	n := &snapshotNode{fragment: s.Fragment}
+	if s.Ordered != nil {
+		n.ordered = make([]Node, len(s.Ordered))
+		for i, child := range s.Ordered {
+			n.ordered[i] = child.restore()
+		}
+	}
+	if s.Unordered != nil {
+		n.unordered = make(map[string]Node, len(s.Unordered))
+		for name, child := range s.Unordered {
+			n.unordered[name] = child.restore()
+		}
+	}
+	return n
+}
+
+// Snapshot serializes the subtree rooted at n into a form that can later be
+// reconstructed with Restore. Only the fragments and branch structure of the
+// tree are captured; handler-specific state is not preserved.
+func Snapshot(n Node) ([]byte, error) {
+	return json.Marshal(newSnapshot(n))
+}
+
+// Restore parses data produced by Snapshot, returning a Node, which also
+// implements MutableNode, that reproduces the original subtree. The returned node may be modified and
+// flushed independently of whatever tree it was snapshotted from, then
+// re-snapshotted, allowing callers to round-trip a tree through storage and
+// reapply modifications atomically.
+func Restore(data []byte) (Node, error) {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return s.restore(), nil
+}
+
+// snapshotNode is an in-memory Node produced by Restore.
+type snapshotNode struct {
+	fragment  string
+	ordered   []Node
+	unordered map[string]Node
+}
+
+func (n *snapshotNode) Fragment() string { return n.fragment }
+
+func (n *snapshotNode) Len() int { return len(n.ordered) }
+
+func (n *snapshotNode) OrderedChild(i int) Node {
+	if i = Index(i, len(n.ordered)); i < 0 {
+		return nil
+	}
+	return n.ordered[i]
+}
+
+func (n *snapshotNode) OrderedChildren() []Node {
+	children := make([]Node, len(n.ordered))
+	copy(children, n.ordered)
+	return children
+}
+
+func (n *snapshotNode) UnorderedChild(name string) Node {
+	return n.unordered[name]
+}
+
+func (n *snapshotNode) UnorderedChildren() map[string]Node {
+	children := make(map[string]Node, len(n.unordered))
+	for name, child := range n.unordered {
+		children[name] = child
+	}
+	return children
+}
+
+// SetChild implements MutableNode by replacing or adding an unordered child.
+func (n *snapshotNode) SetChild(name string, c Node) error {
+	if n.unordered == nil {
+		n.unordered = map[string]Node{}
+	}
+	n.unordered[name] = c
+	return nil
+}
+
+// RemoveChild implements MutableNode by removing an unordered child.
+func (n *snapshotNode) RemoveChild(name string) error {
+	if _, ok := n.unordered[name]; !ok {
+		return fmt.Errorf("drill: no child named %q", name)
+	}
+	delete(n.unordered, name)
+	return nil
+}
+
+// AppendChild implements MutableNode by appending an ordered child.
+func (n *snapshotNode) AppendChild(c Node) error {
+	n.ordered = append(n.ordered, c)
+	return nil
+}
+
+// Flush implements MutableNode. Since a snapshotNode exists entirely in
+// memory, Flush is a no-op.
+func (n *snapshotNode) Flush() error {
+	return nil
+}
@@ -0,0 +1,338 @@
+package drill
+
+import (
+	"sort"
+	"sync"
+)
+
+// Order specifies the order in which a Walker visits nodes.
+type Order int
+
+const (
+	// PreOrder visits a node before its children.
+	PreOrder Order = iota
+	// PostOrder visits a node after its children.
+	PostOrder
+	// BreadthFirst visits nodes level by level, nearest the root first.
+	BreadthFirst
+)
+
+// Walker separates traversal policy from the Node interfaces. Its zero value
+// walks an entire tree, PreOrder, visiting every node.
+type Walker struct {
+	// MaxDepth limits how many levels below the root are visited. A value
+	// less than or equal to 0 means no limit.
+	MaxDepth int
+	// Filter, if set, determines whether a node should be visited. Returning
+	// false also prevents descending into the node's children.
+	Filter func(Path, Node) bool
+	// Sort, if set, reorders a node's children before they are visited.
+	Sort func([]Node) []Node
+	// Order controls the order in which nodes are visited.
+	Order Order
+	// FollowHandlers controls whether the Walker descends into children
+	// produced by a secondary handler, as reported by a node that implements
+	// Handled. Nodes that do not implement Handled are unaffected.
+	FollowHandlers bool
+	// OnEnter, if set, is called before a node is visited.
+	OnEnter func(Path, Node)
+	// OnLeave, if set, is called after a node and its children (if visited)
+	// have been processed. Under BreadthFirst, and in WalkParallel, it is
+	// instead called right after the node's own visit, before any of its
+	// children are visited.
+	OnLeave func(Path, Node)
+	// OnError, if set, is called with an error returned by visit, and may
+	// return a different error (or nil, to ignore it and continue the walk).
+	OnError func(Path, Node, error) error
+}
+
+// Handled is implemented by a Node to indicate that some of its children are
+// produced by a secondary handler (for example, a file drilled into through a
+// filesys.HandlerFunc) rather than being plain structural children. A Walker
+// consults IsHandledChild, when FollowHandlers is false, to decide whether to
+// descend into such a child.
+type Handled interface {
+	Node
+	// IsHandledChild reports whether the child produced for the given query
+	// (a name or index, as accepted by Query) came from a secondary handler.
+	IsHandledChild(query interface{}) bool
+}
+
+// children returns the direct children of n as PathElems, ordered children
+// first, followed by unordered children sorted by name.
+func children(n Node) []PathElem {
+	var out []PathElem
+	if o, ok := n.(OrderedBranch); ok {
+		oc := o.OrderedChildren()
+		out = make([]PathElem, 0, len(oc))
+		for i, c := range oc {
+			if c == nil {
+				continue
+			}
+			out = append(out, PathElem{Index: i, Node: c})
+		}
+	}
+	if u, ok := n.(UnorderedBranch); ok {
+		uc := u.UnorderedChildren()
+		names := make([]string, 0, len(uc))
+		for name := range uc {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if uc[name] == nil {
+				continue
+			}
+			out = append(out, PathElem{Name: name, Index: -1, Node: uc[name]})
+		}
+	}
+	return out
+}
+
+// filterHandled reports whether child, reached from parent by query, should be
+// descended into, according to FollowHandlers.
+func (w *Walker) filterHandled(parent Node, query interface{}) bool {
+	if w.FollowHandlers {
+		return true
+	}
+	h, ok := parent.(Handled)
+	if !ok {
+		return true
+	}
+	return !h.IsHandledChild(query)
+}
+
+// sortChildren reorders kids according to Sort, if set.
+func (w *Walker) sortChildren(kids []PathElem) []PathElem {
+	if w.Sort == nil || len(kids) == 0 {
+		return kids
+	}
+	nodes := make([]Node, len(kids))
+	for i, k := range kids {
+		nodes[i] = k.Node
+	}
+	nodes = w.Sort(nodes)
+	byNode := make(map[Node]PathElem, len(kids))
+	for _, k := range kids {
+		byNode[k.Node] = k
+	}
+	sorted := make([]PathElem, 0, len(nodes))
+	for _, nd := range nodes {
+		if k, ok := byNode[nd]; ok {
+			sorted = append(sorted, k)
+		}
+	}
+	return sorted
+}
+
+// query returns the name or index by which a PathElem was reached, suitable
+// for Handled.IsHandledChild.
+func (e PathElem) query() interface{} {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Index
+}
+
+// eligibleChildren returns the children of n that should be descended into,
+// given FollowHandlers and Sort.
+func (w *Walker) eligibleChildren(n Node) []PathElem {
+	kids := children(n)
+	if !w.FollowHandlers {
+		filtered := kids[:0:0]
+		for _, k := range kids {
+			if w.filterHandled(n, k.query()) {
+				filtered = append(filtered, k)
+			}
+		}
+		kids = filtered
+	}
+	return w.sortChildren(kids)
+}
+
+// Walk traverses root according to the options of w, calling visit for each
+// node encountered. If visit returns an error, traversal stops and the error
+// (possibly replaced by OnError) is returned.
+func (w *Walker) Walk(root Node, visit func(Path, Node) error) error {
+	if root == nil {
+		return nil
+	}
+	base := Path{{Index: -1, Node: root}}
+	if w.Order == BreadthFirst {
+		return w.walkBreadthFirst(base, visit)
+	}
+	return w.walkDepthFirst(base, visit)
+}
+
+func (w *Walker) callVisit(path Path, n Node, visit func(Path, Node) error) error {
+	err := visit(path, n)
+	if err != nil && w.OnError != nil {
+		err = w.OnError(path, n, err)
+	}
+	return err
+}
+
+func (w *Walker) walkDepthFirst(path Path, visit func(Path, Node) error) error {
+	n := path[len(path)-1].Node
+	if w.Filter != nil && !w.Filter(path, n) {
+		return nil
+	}
+	if w.OnEnter != nil {
+		w.OnEnter(path, n)
+	}
+	post := w.Order == PostOrder
+	if !post {
+		if err := w.callVisit(path, n, visit); err != nil {
+			return err
+		}
+	}
+	if w.MaxDepth <= 0 || len(path)-1 < w.MaxDepth {
+		for _, k := range w.eligibleChildren(n) {
+			childPath := make(Path, len(path)+1)
+			copy(childPath, path)
+			childPath[len(path)] = k
+			if err := w.walkDepthFirst(childPath, visit); err != nil {
+				return err
+			}
+		}
+	}
+	if post {
+		if err := w.callVisit(path, n, visit); err != nil {
+			return err
+		}
+	}
+	if w.OnLeave != nil {
+		w.OnLeave(path, n)
+	}
+	return nil
+}
+
+func (w *Walker) walkBreadthFirst(base Path, visit func(Path, Node) error) error {
+	queue := []Path{base}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		n := path[len(path)-1].Node
+		if w.Filter != nil && !w.Filter(path, n) {
+			continue
+		}
+		if w.OnEnter != nil {
+			w.OnEnter(path, n)
+		}
+		if err := w.callVisit(path, n, visit); err != nil {
+			return err
+		}
+		if w.OnLeave != nil {
+			w.OnLeave(path, n)
+		}
+		if w.MaxDepth > 0 && len(path)-1 >= w.MaxDepth {
+			continue
+		}
+		for _, k := range w.eligibleChildren(n) {
+			childPath := make(Path, len(path)+1)
+			copy(childPath, path)
+			childPath[len(path)] = k
+			queue = append(queue, childPath)
+		}
+	}
+	return nil
+}
+
+// WalkParallel traverses root like Walk, but fans out visits to children
+// across workers concurrent goroutines. It is intended for trees with
+// expensive-to-produce fragments, such as rendered Markdown or archive
+// extraction. The relative order and concurrency of visits across sibling
+// subtrees is not guaranteed; PostOrder and BreadthFirst have no effect. The
+// first error returned by visit (or by OnError) stops new visits from being
+// started and is returned once all in-flight visits complete.
+func (w *Walker) WalkParallel(root Node, workers int, visit func(Path, Node) error) error {
+	if root == nil {
+		return nil
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	setErr := func(err error) {
+		if err == nil {
+			return
+		}
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+	aborted := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	var spawn func(path Path)
+	spawn = func(path Path) {
+		defer wg.Done()
+		sem <- struct{}{}
+		defer func() { <-sem }()
+		if aborted() {
+			return
+		}
+		n := path[len(path)-1].Node
+		if w.Filter != nil && !w.Filter(path, n) {
+			return
+		}
+		if w.OnEnter != nil {
+			w.OnEnter(path, n)
+		}
+		setErr(w.callVisit(path, n, visit))
+		if w.OnLeave != nil {
+			w.OnLeave(path, n)
+		}
+		if w.MaxDepth > 0 && len(path)-1 >= w.MaxDepth {
+			return
+		}
+		for _, k := range w.eligibleChildren(n) {
+			childPath := make(Path, len(path)+1)
+			copy(childPath, path)
+			childPath[len(path)] = k
+			wg.Add(1)
+			go spawn(childPath)
+		}
+	}
+
+	wg.Add(1)
+	go spawn(Path{{Index: -1, Node: root}})
+	wg.Wait()
+	return firstErr
+}
+
+// Descendants returns a list of all the descendants of the node. If a node
+// does not implement OrderedBranch, then its children are skipped.
+func Descendants(n Node) []Node {
+	d := []Node{}
+	if n == nil {
+		return d
+	}
+	w := Walker{
+		Order: PreOrder,
+		Filter: func(p Path, c Node) bool {
+			if len(p) < 2 {
+				return true
+			}
+			// Only the ordered side of children() sets a non-negative Index;
+			// unordered children are always reached with Index: -1.
+			return p[len(p)-1].Index >= 0
+		},
+	}
+	w.Walk(n, func(p Path, c Node) error {
+		if len(p) > 1 {
+			d = append(d, c)
+		}
+		return nil
+	})
+	return d
+}
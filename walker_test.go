@@ -0,0 +1,79 @@
+package drill_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/anaminus/drill"
+	"github.com/anaminus/drill/filesys"
+	"github.com/anaminus/drill/filesys/markdown"
+)
+
+// assertDescendantsUnique fails t if d contains the same Node more than once,
+// which is the symptom of a child being counted through both its ordered and
+// unordered PathElem.
+func assertDescendantsUnique(t *testing.T, d []drill.Node) {
+	t.Helper()
+	seen := make(map[drill.Node]bool, len(d))
+	for _, n := range d {
+		if seen[n] {
+			t.Fatalf("Descendants returned node %v more than once", n)
+		}
+		seen[n] = true
+	}
+}
+
+func TestDescendantsFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"b.txt":     {Data: []byte("b")},
+		"dir/c.txt": {Data: []byte("c")},
+	}
+	root, err := filesys.NewFS(fsys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := drill.Descendants(root)
+	assertDescendantsUnique(t, d)
+	// a.txt, b.txt, dir, dir/c.txt.
+	if len(d) != 4 {
+		t.Fatalf("Descendants returned %d nodes, want 4", len(d))
+	}
+}
+
+func TestDescendantsMarkdown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"doc.md": {Data: []byte("# A\nbody a\n\n# B\nbody b\n")},
+	}
+	root := markdown.NewHandler()(fsys, "doc.md")
+	d := drill.Descendants(root)
+	assertDescendantsUnique(t, d)
+	// Section A, its orphaned body section, Section B, its orphaned body
+	// section.
+	if len(d) != 4 {
+		t.Fatalf("Descendants returned %d nodes, want 4", len(d))
+	}
+}
+
+func TestWalkPreOrder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("a")},
+		"b.txt": {Data: []byte("b")},
+	}
+	root, err := filesys.NewFS(fsys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var visited int
+	w := drill.Walker{}
+	if err := w.Walk(root, func(p drill.Path, n drill.Node) error {
+		visited++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// root + 2 files.
+	if visited != 3 {
+		t.Fatalf("Walk visited %d nodes, want 3", visited)
+	}
+}